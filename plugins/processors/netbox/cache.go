@@ -0,0 +1,108 @@
+package netbox
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/influxdata/telegraf/plugins/processors/netbox/client"
+)
+
+// persistedDevice is the on-disk, JSON-serializable mirror of NetboxDevice.
+// NetboxDevice's fields stay unexported since nothing outside this package
+// should touch cached entries directly.
+type persistedDevice struct {
+	IP      string                 `json:"ip"`
+	Region  *client.Region         `json:"region,omitempty"`
+	Site    *client.Site           `json:"site,omitempty"`
+	Tenant  *client.Tenant         `json:"tenant,omitempty"`
+	Device  *client.Device         `json:"device,omitempty"`
+	VM      *client.VirtualMachine `json:"vm,omitempty"`
+	Cluster *client.Cluster        `json:"cluster,omitempty"`
+	AddedAt time.Time              `json:"added_at"`
+}
+
+// loadPersistedCache warm-starts i.data from the JSON-lines file written by
+// flushPersistedCache, so a restart doesn't have to re-resolve every hot IP
+// against Netbox from scratch. A missing or unreadable file isn't fatal; the
+// cache just starts cold.
+func (i *NetboxData) loadPersistedCache() {
+	f, err := os.Open(i.cachePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logPrintf("Unable to open netbox cache file %s: %v", i.cachePath, err)
+		}
+		return
+	}
+	defer f.Close()
+
+	loaded := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry persistedDevice
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			logPrintf("Skipping malformed netbox cache line: %v", err)
+			continue
+		}
+		i.data[entry.IP] = &NetboxDevice{
+			region:  entry.Region,
+			site:    entry.Site,
+			tenant:  entry.Tenant,
+			device:  entry.Device,
+			vm:      entry.VM,
+			cluster: entry.Cluster,
+			addedAt: entry.AddedAt,
+		}
+		loaded++
+	}
+	logPrintf("Warm-started netbox cache with %d entries from %s", loaded, i.cachePath)
+}
+
+// flushPersistedCache atomically rewrites the cache file with the current
+// ip -> device map, one JSON object per line, via a temp file + rename so a
+// crash mid-write never leaves a truncated cache behind.
+func (i *NetboxData) flushPersistedCache() {
+	tmpPath := i.cachePath + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		logPrintf("Unable to write netbox cache file %s: %v", tmpPath, err)
+		return
+	}
+
+	i.Lock()
+	enc := json.NewEncoder(f)
+	for ip, device := range i.data {
+		entry := persistedDevice{
+			IP:      ip,
+			Region:  device.region,
+			Site:    device.site,
+			Tenant:  device.tenant,
+			Device:  device.device,
+			VM:      device.vm,
+			Cluster: device.cluster,
+			AddedAt: device.addedAt,
+		}
+		if err := enc.Encode(entry); err != nil {
+			logPrintf("Unable to encode netbox cache entry for %s: %v", ip, err)
+		}
+	}
+	i.Unlock()
+
+	if err := f.Close(); err != nil {
+		logPrintf("Unable to close netbox cache file %s: %v", tmpPath, err)
+		return
+	}
+	if err := os.Rename(tmpPath, i.cachePath); err != nil {
+		logPrintf("Unable to install netbox cache file %s: %v", i.cachePath, err)
+	}
+}
+
+// persistLoop periodically flushes the cache to disk until the process exits.
+func (i *NetboxData) persistLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		i.flushPersistedCache()
+	}
+}
@@ -0,0 +1,52 @@
+package netbox
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/plugins/processors/netbox/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPersistedCacheRoundTrip(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "netbox_cache.jsonl")
+
+	ttl, _ := time.ParseDuration("1h")
+	addedAt := time.Now()
+	source := &NetboxData{
+		cachePath: cachePath,
+		data: map[string]*NetboxDevice{
+			"141.193.3.5": &NetboxDevice{
+				region:  &client.Region{Name: "US_WEST"},
+				site:    &client.Site{Name: "sjc1"},
+				device:  &client.Device{Name: "br1-sjc1"},
+				addedAt: addedAt,
+			},
+		},
+	}
+	source.flushPersistedCache()
+
+	loaded := &NetboxData{
+		cachePath: cachePath,
+		entryTTL:  ttl,
+		data:      make(map[string]*NetboxDevice),
+	}
+	loaded.loadPersistedCache()
+
+	device, ok := loaded.data["141.193.3.5"]
+	assert.True(t, ok)
+	assert.Equal(t, device.site.Name, "sjc1")
+	assert.Equal(t, device.region.Name, "US_WEST")
+	assert.Equal(t, device.device.Name, "br1-sjc1")
+	assert.WithinDuration(t, device.addedAt, addedAt, time.Second)
+}
+
+func TestLoadPersistedCacheMissingFileIsNotFatal(t *testing.T) {
+	loaded := &NetboxData{
+		cachePath: filepath.Join(t.TempDir(), "does-not-exist.jsonl"),
+		data:      make(map[string]*NetboxDevice),
+	}
+	loaded.loadPersistedCache()
+	assert.Len(t, loaded.data, 0)
+}
@@ -0,0 +1,156 @@
+// Package client is a small typed client for the subset of the Netbox API
+// the netbox processor needs. It replaces ad-hoc map[string]interface{}
+// casts with real structs, and understands the schema differences between
+// Netbox 2.x and 3.x+ (see APIVersion).
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// APIVersion selects which Netbox schema generation to speak. Older
+// deployments (2.x) nest interfaces under an ip-address's "interface" key
+// and require following the device/site URLs to reach site and region.
+// Newer deployments (3.x+) embed site directly on the device and carry an
+// ip-address's interface under its "assigned_object" key instead.
+type APIVersion string
+
+const (
+	APIVersion2 APIVersion = "2"
+	APIVersion3 APIVersion = "3"
+)
+
+// Client is a thin wrapper around http.Client that knows how to talk to a
+// Netbox instance's REST API and decode its paginated responses into typed
+// results.
+type Client struct {
+	http       *http.Client
+	baseURL    string
+	token      string
+	apiVersion APIVersion
+	observer   RequestObserver
+
+	IPAddresses     *IPAddressService
+	Devices         *DeviceService
+	Interfaces      *InterfaceService
+	Sites           *SiteService
+	Regions         *RegionService
+	Racks           *RackService
+	Tenants         *TenantService
+	Clusters        *ClusterService
+	VirtualMachines *VirtualMachineService
+	Prefixes        *PrefixService
+	VRFs            *VRFService
+}
+
+// New returns a Client targeting the given Netbox base address (host only,
+// no scheme) using the given API token and schema version.
+func New(netboxAddr, token string, apiVersion APIVersion, httpClient *http.Client) *Client {
+	if apiVersion == "" {
+		apiVersion = APIVersion2
+	}
+	c := &Client{
+		http:       httpClient,
+		baseURL:    fmt.Sprintf("https://%s/api", netboxAddr),
+		token:      token,
+		apiVersion: apiVersion,
+	}
+	c.IPAddresses = &IPAddressService{client: c}
+	c.Devices = &DeviceService{client: c}
+	c.Interfaces = &InterfaceService{client: c}
+	c.Sites = &SiteService{client: c}
+	c.Regions = &RegionService{client: c}
+	c.Racks = &RackService{client: c}
+	c.Tenants = &TenantService{client: c}
+	c.Clusters = &ClusterService{client: c}
+	c.VirtualMachines = &VirtualMachineService{client: c}
+	c.Prefixes = &PrefixService{client: c}
+	c.VRFs = &VRFService{client: c}
+	return c
+}
+
+// APIVersion reports which Netbox schema generation this client is
+// configured to speak.
+func (c *Client) APIVersion() APIVersion {
+	return c.apiVersion
+}
+
+func (c *Client) fetch(path string) ([]byte, error) {
+	url := path
+	if len(url) == 0 || url[0] == '/' {
+		url = c.baseURL + path
+	}
+	start := time.Now()
+	statusCode := 0
+	body, err := c.doFetch(url)
+	if err == nil {
+		// doFetch folds a non-200 status into err, so pull it back out via
+		// the sentinel it wraps for the observer's sake.
+		statusCode = http.StatusOK
+	} else if se, ok := err.(*statusError); ok {
+		statusCode = se.statusCode
+	}
+	if c.observer != nil {
+		c.observer.ObserveRequest(path, statusCode, time.Since(start), err)
+	}
+	return body, err
+}
+
+// statusError carries the HTTP status code of a non-200 response so fetch
+// can report it to the observer without re-parsing the error string.
+type statusError struct {
+	statusCode int
+	url        string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("netbox request to %s failed with status %d", e.url, e.statusCode)
+}
+
+func (c *Client) doFetch(url string) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Authorization", "Token "+c.token)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{statusCode: resp.StatusCode, url: url}
+	}
+	return body, nil
+}
+
+// getOne decodes a single-object response (e.g. /dcim/devices/{id}/) into out.
+func (c *Client) getOne(path string, out interface{}) error {
+	body, err := c.fetch(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, out)
+}
+
+// getList decodes a paginated list response and unmarshals its results into
+// out, which must be a pointer to a slice of the expected element type.
+func (c *Client) getList(path string, out interface{}) error {
+	body, err := c.fetch(path)
+	if err != nil {
+		return err
+	}
+	var env envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return err
+	}
+	return json.Unmarshal(env.Results, out)
+}
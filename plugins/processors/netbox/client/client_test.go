@@ -0,0 +1,43 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var ipRawV2 = `{"count":1,"next":null,"previous":null,"results":[{"id":1531,"address":"141.193.3.5/32","interface":{"id":42419,"name":"lo0.0","device":{"id":1019,"url":"https://netbox.roblox.local/api/dcim/devices/1019/","name":"br1-sjc1"}}}]}`
+
+var deviceRawV2 = `{"id":1019,"url":"https://netbox.roblox.local/api/dcim/devices/1019/","name":"br1-sjc1","site":{"id":14,"url":"https://netbox.roblox.local/api/dcim/sites/14/","name":"fra1"}}`
+
+var siteRawV2 = `{"id":14,"url":"https://netbox.roblox.local/api/dcim/sites/14/","name":"fra1","region":{"id":3,"url":"https://netbox.roblox.local/api/dcim/regions/3/","name":"EU"}}`
+
+func TestIPAddressUnmarshal(t *testing.T) {
+	var env envelope
+	err := json.Unmarshal([]byte(ipRawV2), &env)
+	assert.NoError(t, err)
+
+	var results []IPAddress
+	err = json.Unmarshal(env.Results, &results)
+	assert.NoError(t, err)
+	assert.Equal(t, len(results), 1)
+	assert.Equal(t, results[0].Interface.ID, 42419)
+	assert.Equal(t, results[0].Interface.Device.Name, "br1-sjc1")
+}
+
+func TestDeviceUnmarshal(t *testing.T) {
+	var device Device
+	err := json.Unmarshal([]byte(deviceRawV2), &device)
+	assert.NoError(t, err)
+	assert.Equal(t, device.Name, "br1-sjc1")
+	assert.Equal(t, device.Site.Name, "fra1")
+}
+
+func TestSiteUnmarshal(t *testing.T) {
+	var site Site
+	err := json.Unmarshal([]byte(siteRawV2), &site)
+	assert.NoError(t, err)
+	assert.Equal(t, site.Name, "fra1")
+	assert.Equal(t, site.Region.Name, "EU")
+}
@@ -0,0 +1,16 @@
+package client
+
+import "time"
+
+// RequestObserver lets a caller (the netbox processor) instrument every
+// HTTP round trip the client makes, without the client itself depending on
+// telegraf's stats machinery.
+type RequestObserver interface {
+	ObserveRequest(path string, statusCode int, duration time.Duration, err error)
+}
+
+// SetObserver installs (or clears, with nil) the RequestObserver notified
+// after every request.
+func (c *Client) SetObserver(observer RequestObserver) {
+	c.observer = observer
+}
@@ -0,0 +1,168 @@
+package client
+
+import "fmt"
+
+// IPAddressService resolves a single IP to whatever it's assigned to.
+type IPAddressService struct{ client *Client }
+
+// Get looks up the device (or VM) interface an IP is assigned to. On
+// APIVersion2 the interface is embedded directly in the result; on
+// APIVersion3 it's under assigned_object instead.
+func (s *IPAddressService) Get(ip string) (*IPAddress, error) {
+	var results []IPAddress
+	path := fmt.Sprintf("/ipam/ip-addresses/?q=%s%%2F32", ip)
+	if err := s.client.getList(path, &results); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("No results found in netbox for ip %s", ip)
+	}
+	addr := &results[0]
+	if s.client.apiVersion == APIVersion3 && addr.Interface == nil {
+		addr.Interface = addr.AssignedObject
+	}
+	return addr, nil
+}
+
+// DeviceService resolves devices by ID or by name.
+type DeviceService struct{ client *Client }
+
+func (s *DeviceService) Get(id int) (*Device, error) {
+	var device Device
+	path := fmt.Sprintf("/dcim/devices/%d/", id)
+	if err := s.client.getOne(path, &device); err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+func (s *DeviceService) GetByURL(url string) (*Device, error) {
+	var device Device
+	if err := s.client.getOne(url, &device); err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// GetConfigContext fetches a device along with its merged config_context
+// (rendered by Netbox from role/site/tenant/cluster/tag-scoped contexts).
+func (s *DeviceService) GetConfigContext(id int) (*Device, error) {
+	var device Device
+	path := fmt.Sprintf("/dcim/devices/%d/?include=config_context", id)
+	if err := s.client.getOne(path, &device); err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+func (s *DeviceService) GetByName(name string) (*Device, error) {
+	var results []Device
+	path := fmt.Sprintf("/dcim/devices/?name=%s", name)
+	if err := s.client.getList(path, &results); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("No results found in netbox for device %s", name)
+	}
+	return &results[0], nil
+}
+
+// InterfaceService resolves an interface by device ID and name through
+// /dcim/interfaces/, on every API version.
+type InterfaceService struct{ client *Client }
+
+func (s *InterfaceService) Get(deviceID int, name string) (*Interface, error) {
+	var results []Interface
+	path := fmt.Sprintf("/dcim/interfaces/?device_id=%d&name=%s", deviceID, name)
+	if err := s.client.getList(path, &results); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("No results found in netbox for interface %s on device %d", name, deviceID)
+	}
+	return &results[0], nil
+}
+
+// SiteService resolves a site, following the device -> site URL on
+// APIVersion2 deployments where the ip-addresses response doesn't embed it.
+type SiteService struct{ client *Client }
+
+func (s *SiteService) GetByURL(url string) (*Site, error) {
+	var site Site
+	if err := s.client.getOne(url, &site); err != nil {
+		return nil, err
+	}
+	return &site, nil
+}
+
+// RegionService resolves a region, following the site -> region URL.
+type RegionService struct{ client *Client }
+
+func (s *RegionService) GetByURL(url string) (*Region, error) {
+	var region Region
+	if err := s.client.getOne(url, &region); err != nil {
+		return nil, err
+	}
+	return &region, nil
+}
+
+type RackService struct{ client *Client }
+
+func (s *RackService) GetByURL(url string) (*Rack, error) {
+	var rack Rack
+	if err := s.client.getOne(url, &rack); err != nil {
+		return nil, err
+	}
+	return &rack, nil
+}
+
+type TenantService struct{ client *Client }
+
+func (s *TenantService) GetByURL(url string) (*Tenant, error) {
+	var tenant Tenant
+	if err := s.client.getOne(url, &tenant); err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+type ClusterService struct{ client *Client }
+
+func (s *ClusterService) GetByURL(url string) (*Cluster, error) {
+	var cluster Cluster
+	if err := s.client.getOne(url, &cluster); err != nil {
+		return nil, err
+	}
+	return &cluster, nil
+}
+
+type VirtualMachineService struct{ client *Client }
+
+func (s *VirtualMachineService) GetByURL(url string) (*VirtualMachine, error) {
+	var vm VirtualMachine
+	if err := s.client.getOne(url, &vm); err != nil {
+		return nil, err
+	}
+	return &vm, nil
+}
+
+type PrefixService struct{ client *Client }
+
+func (s *PrefixService) List(limit int) ([]Prefix, error) {
+	var results []Prefix
+	path := fmt.Sprintf("/ipam/prefixes/?limit=%d", limit)
+	if err := s.client.getList(path, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+type VRFService struct{ client *Client }
+
+func (s *VRFService) GetByURL(url string) (*VRF, error) {
+	var vrf VRF
+	if err := s.client.getOne(url, &vrf); err != nil {
+		return nil, err
+	}
+	return &vrf, nil
+}
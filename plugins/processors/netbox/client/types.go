@@ -0,0 +1,121 @@
+package client
+
+import "encoding/json"
+
+// envelope is Netbox's paginated list response shape, common to every
+// list endpoint regardless of API version.
+type envelope struct {
+	Count    int             `json:"count"`
+	Next     *string         `json:"next"`
+	Previous *string         `json:"previous"`
+	Results  json.RawMessage `json:"results"`
+}
+
+// Label is the {value, label} pair Netbox uses for choice fields such as
+// form_factor and face.
+type Label struct {
+	Value int    `json:"value"`
+	Label string `json:"label"`
+}
+
+type Region struct {
+	ID   int    `json:"id"`
+	URL  string `json:"url"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type Site struct {
+	ID     int     `json:"id"`
+	URL    string  `json:"url"`
+	Name   string  `json:"name"`
+	Slug   string  `json:"slug"`
+	Region *Region `json:"region,omitempty"`
+}
+
+type Rack struct {
+	ID   int    `json:"id"`
+	URL  string `json:"url"`
+	Name string `json:"name"`
+}
+
+type Tenant struct {
+	ID   int    `json:"id"`
+	URL  string `json:"url"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type VRF struct {
+	ID   int    `json:"id"`
+	URL  string `json:"url"`
+	Name string `json:"name"`
+}
+
+type Cluster struct {
+	ID   int    `json:"id"`
+	URL  string `json:"url"`
+	Name string `json:"name"`
+	Site *Site  `json:"site,omitempty"`
+}
+
+// InterfaceConnection is the 2.x shape of a cable termination, reached via
+// an interface's interface_connection field.
+type InterfaceConnection struct {
+	Interface *Interface `json:"interface,omitempty"`
+}
+
+type Interface struct {
+	ID                  int                  `json:"id"`
+	URL                 string               `json:"url"`
+	Name                string               `json:"name"`
+	Device              *Device              `json:"device,omitempty"`
+	VirtualMachine      *VirtualMachine      `json:"virtual_machine,omitempty"`
+	MTU                 int                  `json:"mtu"`
+	MACAddress          string               `json:"mac_address"`
+	Lag                 *Interface           `json:"lag,omitempty"`
+	FormFactor          *Label               `json:"form_factor,omitempty"`
+	InterfaceConnection *InterfaceConnection `json:"interface_connection,omitempty"`
+}
+
+type Device struct {
+	ID            int                    `json:"id"`
+	URL           string                 `json:"url"`
+	Name          string                 `json:"name"`
+	Site          *Site                  `json:"site,omitempty"`
+	Rack          *Rack                  `json:"rack,omitempty"`
+	Tenant        *Tenant                `json:"tenant,omitempty"`
+	Position      float64                `json:"position"`
+	Face          *Label                 `json:"face,omitempty"`
+	Cluster       *Cluster               `json:"cluster,omitempty"`
+	ConfigContext map[string]interface{} `json:"config_context,omitempty"`
+}
+
+type VirtualMachine struct {
+	ID      int      `json:"id"`
+	URL     string   `json:"url"`
+	Name    string   `json:"name"`
+	Cluster *Cluster `json:"cluster,omitempty"`
+}
+
+type Prefix struct {
+	ID     int     `json:"id"`
+	URL    string  `json:"url"`
+	Prefix string  `json:"prefix"`
+	Site   *Site   `json:"site,omitempty"`
+	Tenant *Tenant `json:"tenant,omitempty"`
+	VRF    *VRF    `json:"vrf,omitempty"`
+}
+
+// IPAddress models both the 2.x shape (interface embeds device, which must
+// be followed to reach site/region) and the 3.x shape (assigned_object
+// embeds the interface and the device already carries its site).
+type IPAddress struct {
+	ID             int        `json:"id"`
+	URL            string     `json:"url"`
+	Address        string     `json:"address"`
+	Tenant         *Tenant    `json:"tenant,omitempty"`
+	VRF            *VRF       `json:"vrf,omitempty"`
+	Interface      *Interface `json:"interface,omitempty"`       // 2.x
+	AssignedObject *Interface `json:"assigned_object,omitempty"` // 3.x+
+}
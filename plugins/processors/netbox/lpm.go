@@ -0,0 +1,99 @@
+package netbox
+
+import (
+	"net"
+
+	"github.com/influxdata/telegraf/plugins/processors/netbox/client"
+)
+
+// prefixMeta is the metadata a prefix-mode trie node carries for the most
+// specific prefix that matched a lookup.
+type prefixMeta struct {
+	site   *client.Site
+	region *client.Region
+	tenant *client.Tenant
+}
+
+// trieNode is one bit of a binary LPM trie: children[0]/children[1] are the
+// next bit being 0/1, and meta is set when a prefix terminates at this
+// depth.
+type trieNode struct {
+	children [2]*trieNode
+	meta     *prefixMeta
+}
+
+// trie holds the separate IPv4 and IPv6 tries built from a Netbox prefix
+// refresh. A nil trie (the zero value) always misses, so callers can fall
+// back to the per-IP lookup path.
+type trie struct {
+	root4 *trieNode
+	root6 *trieNode
+}
+
+func bitAt(key []byte, i int) int {
+	return int((key[i/8] >> uint(7-i%8)) & 1)
+}
+
+func insert(root *trieNode, key []byte, prefixLen int, meta *prefixMeta) *trieNode {
+	if root == nil {
+		root = &trieNode{}
+	}
+	node := root
+	for i := 0; i < prefixLen; i++ {
+		bit := bitAt(key, i)
+		if node.children[bit] == nil {
+			node.children[bit] = &trieNode{}
+		}
+		node = node.children[bit]
+	}
+	node.meta = meta
+	return root
+}
+
+// lookupLongestMatch walks the trie bit by bit, remembering the most recent
+// node that carried metadata, and returns it once the key or trie is
+// exhausted -- i.e. longest-prefix-match.
+func lookupLongestMatch(root *trieNode, key []byte, maxBits int) *prefixMeta {
+	var best *prefixMeta
+	node := root
+	for i := 0; i < maxBits && node != nil; i++ {
+		if node.meta != nil {
+			best = node.meta
+		}
+		node = node.children[bitAt(key, i)]
+	}
+	if node != nil && node.meta != nil {
+		best = node.meta
+	}
+	return best
+}
+
+// insert adds a CIDR prefix's metadata to the appropriate (v4/v6) root.
+func (t *trie) insert(cidr string, meta *prefixMeta) {
+	_, ipnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return
+	}
+	ones, bits := ipnet.Mask.Size()
+	if bits == 32 {
+		t.root4 = insert(t.root4, ipnet.IP.To4(), ones, meta)
+	} else {
+		t.root6 = insert(t.root6, ipnet.IP.To16(), ones, meta)
+	}
+}
+
+// lookup returns the metadata of the most specific prefix containing ip, or
+// nil on a miss.
+func (t *trie) lookup(ip string) *prefixMeta {
+	if t == nil {
+		return nil
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return nil
+	}
+	if v4 := addr.To4(); v4 != nil {
+		return lookupLongestMatch(t.root4, v4, 32)
+	}
+	return lookupLongestMatch(t.root6, addr.To16(), 128)
+}
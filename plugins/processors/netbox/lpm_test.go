@@ -0,0 +1,25 @@
+package netbox
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/plugins/processors/netbox/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrieLongestPrefixMatch(t *testing.T) {
+	tr := &trie{}
+	tr.insert("10.0.0.0/8", &prefixMeta{site: &client.Site{Name: "site-wide"}})
+	tr.insert("10.1.0.0/16", &prefixMeta{site: &client.Site{Name: "site-mid"}})
+	tr.insert("10.1.2.0/24", &prefixMeta{site: &client.Site{Name: "site-narrow"}})
+
+	assert.Equal(t, tr.lookup("10.1.2.5").site.Name, "site-narrow")
+	assert.Equal(t, tr.lookup("10.1.3.5").site.Name, "site-mid")
+	assert.Equal(t, tr.lookup("10.2.0.5").site.Name, "site-wide")
+	assert.Nil(t, tr.lookup("192.168.1.1"))
+}
+
+func TestTrieNilMisses(t *testing.T) {
+	var tr *trie
+	assert.Nil(t, tr.lookup("10.0.0.1"))
+}
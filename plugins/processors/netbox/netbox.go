@@ -2,11 +2,11 @@ package netbox
 
 import (
 	"crypto/tls"
-	"encoding/json"
 	"fmt"
 	"github.com/influxdata/telegraf"
+	tlsint "github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/processors"
-	"io/ioutil"
+	"github.com/influxdata/telegraf/plugins/processors/netbox/client"
 	"log"
 	"net/http"
 	"strings"
@@ -22,157 +22,537 @@ var sampleConfig = `
       netbox_addr = "netbox.blah.com"
     [[processors.netbox.netbox_token]]
       netbox_token = "00abcd007"
+    ## Optional TLS config for talking to netbox_addr.
+    # tls_ca = "/etc/telegraf/ca.pem"
+    # tls_cert = "/etc/telegraf/cert.pem"
+    # tls_key = "/etc/telegraf/key.pem"
+    ## Use TLS but skip chain & host verification
+    # insecure_skip_verify = false
+    ## Netbox API version this instance speaks. "2" (default) expects
+    ## interfaces nested under ip-addresses results and device/site/region
+    ## reachable only by following their urls. "3" expects site embedded
+    ## directly on device and the ip's interface under assigned_object.
+    [[processors.netbox.netbox_api_version]]
+      netbox_api_version = "2"
 
     ## General params
     [[processors.netbox.preserve_original]]
       preserve_original = true
-    ## Netbox cache per entry TTL
+    ## Netbox cache per entry TTL. An entry older than this is never served
+    ## and is refetched synchronously.
     [[processors.netbox.entry_ttl]]
       entry_ttl = 4h
-    
+    ## Optional soft TTL, shorter than entry_ttl. An entry older than this
+    ## (but still within entry_ttl) is served immediately while a refresh is
+    ## enqueued on the background worker pool, so a slow Netbox never stalls
+    ## Apply(). Defaults to entry_ttl, which disables the soft window.
+    [[processors.netbox.soft_entry_ttl]]
+      soft_entry_ttl = 1h
+    ## Number of background workers processing soft-expiry refreshes.
+    [[processors.netbox.refresh_workers]]
+      refresh_workers = 4
+
+    ## Optional path to persist the ip-to-device cache to disk as JSON lines,
+    ## so a restart warm-starts from the last known-good data instead of
+    ## re-resolving every hot IP against Netbox. Left empty, the cache is
+    ## in-memory only.
+    [[processors.netbox.cache_path]]
+      cache_path = "/var/lib/telegraf/netbox_cache.jsonl"
+    [[processors.netbox.cache_flush_interval]]
+      cache_flush_interval = 5m
+
+    ## For high-cardinality streams, resolve ip-to-device/ip-to-vm tags via an
+    ## in-memory longest-prefix-match trie built from /ipam/prefixes/ instead
+    ## of a per-IP Netbox round trip. Falls back to the per-IP lookup on a
+    ## trie miss.
+    [[processors.netbox.prefix_mode]]
+      prefix_mode = false
+    [[processors.netbox.prefix_refresh_interval]]
+      prefix_refresh_interval = 30m
+
+    ## Top-level keys to pull out of a device's merged Netbox config_context
+    ## and add as tags, used by the device-to-config-context transform.
+    [[processors.netbox.config_context_tags]]
+      config_context_tags = ["env", "service_group", "criticality"]
+
     ## Mapping of transform to use to the Tags to be transformed
     ##  <transform-name> = [ <tag-key>...]
     #
     ## Supported transforms are:
-    ## ip-to-device: Convert an ip address to its parent device+site+region from netbox
+    ## ip-to-device: Convert an ip address to its parent device+site+region from netbox.
+    ##   Falls through to the owning VM+cluster when the ip belongs to a VM interface.
+    ##   In prefix_mode, also adds tenant if the matched prefix has one.
+    ## ip-to-vm: Convert an ip address to its parent VM+cluster+site+region from netbox
+    ## device-to-interface: Resolve interface metadata (mtu, mac, lag, form-factor) for a device+interface tag pair
+    ## device-to-rack: Add rack+position+face for a device tag
+    ## device-to-tenant: Add tenant for a device tag
+    ## interface-to-cable: Add far-side device+interface for a device+interface tag pair
+    ## device-to-config-context: Add the config_context_tags keys found in a device's merged config_context
     [[processors.netbox.transforms]]
       ip-to-device = ["source-address", "destination-address"]
 `
 
-type NetboxElement struct {
-	id   float64
-	url  string
-	name string
-}
-
-func (e *NetboxElement) parse(eType string, data []byte) error {
-	var result map[string]interface{}
-	switch eType {
-	case "device":
-		var d map[string]interface{}
-		if err := json.Unmarshal(data, &d); err != nil {
-			return err
-		}
-		tmp := d["results"].([]interface{})
-		if len(tmp) == 0 {
-			return fmt.Errorf("No results found in netbox")
-		}
-		result = tmp[0].(map[string]interface{})
-		result = result["interface"].(map[string]interface{})
-	case "site", "region":
-		if err := json.Unmarshal(data, &result); err != nil {
-			return err
-		}
-	}
-
-	if d, ok := result[eType].(map[string]interface{}); ok {
-		e.id = d["id"].(float64)
-		e.name = d["name"].(string)
-		e.url = d["url"].(string)
-	} else {
-		return fmt.Errorf("Failed to parse result to netbox element")
-	}
-	return nil
+type NetboxDevice struct {
+	region  *client.Region
+	site    *client.Site
+	tenant  *client.Tenant
+	device  *client.Device
+	vm      *client.VirtualMachine
+	cluster *client.Cluster
+	addedAt time.Time
 }
 
-type NetboxDevice struct {
-	region, site, device *NetboxElement
-	addedAt              time.Time
+// cacheEntry wraps a typed lookup result with the time it was cached, so
+// each entity type can carry its own TTL bookkeeping independent of the
+// ip -> device cache.
+type cacheEntry struct {
+	data    interface{}
+	addedAt time.Time
 }
 
-type Client struct {
-	*http.Client
+// cachedRack bundles the rack along with the position/face pulled off the
+// same device body, so a single device-keyed cache entry covers getRack's
+// whole return value.
+type cachedRack struct {
+	rack     *client.Rack
+	position float64
+	face     string
 }
 
 type NetboxData struct {
-	data                    map[string]*NetboxDevice
-	entryTTL                time.Duration
-	netboxAddr, netboxToken string
-	client                  *Client
+	data           map[string]*NetboxDevice
+	interfaces     map[string]*cacheEntry
+	racks          map[string]*cacheEntry
+	tenants        map[string]*cacheEntry
+	cables         map[string]*cacheEntry
+	configContexts map[int]*cacheEntry
+	missed         map[string]time.Time
+	prefixTrie     *trie
+	softTTL        time.Duration
+	entryTTL       time.Duration
+	cachePath      string
+	refreshCh      chan string
+	refreshing     map[string]bool
+	client         *client.Client
+	stats          *netboxStats
 	sync.Mutex
 }
 
-func (i *NetboxData) query(query string) ([]byte, error) {
-	req, err := http.NewRequest("GET", query, nil)
+// refreshPrefixTrie pulls the full prefix list from Netbox, builds a fresh
+// LPM trie off to the side, and atomically swaps it in under the mutex so
+// concurrent Apply() calls never block on a refresh.
+func (i *NetboxData) refreshPrefixTrie() {
+	prefixes, err := i.client.Prefixes.List(0)
 	if err != nil {
-		return nil, err
+		logPrintf("Unable to refresh netbox prefix cache: %v\n", err)
+		i.stats.incrRefreshFailure()
+		return
 	}
-	req.Header.Add("Authorization", i.netboxToken)
-	resp, err := i.client.Do(req)
-	if err != nil {
-		return nil, err
+	t := &trie{}
+	siteRegions := make(map[string]*client.Region)
+	for _, p := range prefixes {
+		meta := &prefixMeta{site: p.Site, tenant: p.Tenant}
+		if p.Site != nil {
+			if region, ok := siteRegions[p.Site.URL]; ok {
+				meta.region = region
+			} else if p.Site.Region != nil {
+				meta.region = p.Site.Region
+				siteRegions[p.Site.URL] = p.Site.Region
+			} else if fullSite, err := i.client.Sites.GetByURL(p.Site.URL); err == nil && fullSite.Region != nil {
+				meta.region = fullSite.Region
+				siteRegions[p.Site.URL] = fullSite.Region
+			}
+		}
+		t.insert(p.Prefix, meta)
 	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	i.Lock()
+	i.prefixTrie = t
+	i.Unlock()
+	i.stats.setCacheSize("prefix", len(prefixes))
+}
+
+func (i *NetboxData) prefixRefreshLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	for range ticker.C {
+		i.refreshPrefixTrie()
 	}
-	return body, err
 }
 
 func (i *NetboxData) queryNetboxDevice(ip string) (*NetboxDevice, error) {
-	netboxUri := fmt.Sprintf("https://%s/api", i.netboxAddr)
-	device := &NetboxElement{}
-	site := &NetboxElement{}
-	region := &NetboxElement{}
-
-	// We need to do 3 queries here for device, region and site
-	// We get the subsequent query URL from the previous query result
-	url := fmt.Sprintf("%s/ipam/ip-addresses/?q=%s%%2F32", netboxUri, ip)
-	body, err := i.query(url)
+	addr, err := i.client.IPAddresses.Get(ip)
 	if err != nil {
 		return nil, err
 	}
-	if err := device.parse("device", body); err != nil {
-		return nil, err
+	if addr.Interface == nil {
+		return nil, fmt.Errorf("ip %s is not assigned to any interface", ip)
+	}
+	if addr.Interface.Device != nil {
+		return i.resolveDevice(addr.Interface.Device)
 	}
-	body, err = i.query(device.url)
+	if addr.Interface.VirtualMachine != nil {
+		return i.resolveVM(addr.Interface.VirtualMachine)
+	}
+	return nil, fmt.Errorf("ip %s's interface is assigned to neither a device nor a VM", ip)
+}
+
+func (i *NetboxData) resolveDevice(deviceRef *client.Device) (*NetboxDevice, error) {
+	device, err := i.client.Devices.GetByURL(deviceRef.URL)
 	if err != nil {
 		return nil, err
 	}
-	if err := site.parse("site", body); err != nil {
-		return nil, err
+	if device.Site == nil {
+		return nil, fmt.Errorf("device %s has no site assigned", device.Name)
 	}
-	body, err = i.query(site.url)
+
+	var site *client.Site
+	var region *client.Region
+	if i.client.APIVersion() == client.APIVersion3 {
+		// 3.x+ embeds the full site (and its region) directly on the
+		// device, so no extra hop is needed.
+		site = device.Site
+		region = site.Region
+	} else {
+		// 2.x only embeds a site summary on the device; fetch the full
+		// site object to reach its region.
+		site, err = i.client.Sites.GetByURL(device.Site.URL)
+		if err != nil {
+			return nil, err
+		}
+		region = site.Region
+	}
+	if region == nil {
+		// The embedded site (v3's device.Site, or a v2 site summary that
+		// somehow still lacks a region) may simply not carry a region
+		// reference. Re-fetch the full site object rather than decoding
+		// the site's own URL as a Region.
+		fullSite, err := i.client.Sites.GetByURL(site.URL)
+		if err != nil {
+			return nil, err
+		}
+		region = fullSite.Region
+	}
+
+	return &NetboxDevice{
+		region: region, site: site, device: device, addedAt: time.Now()}, nil
+}
+
+// resolveVM resolves a VM's cluster and, through it, site/region, mirroring
+// resolveDevice's device -> site -> region chain. A lot of telemetry
+// originates from VMs now, and Netbox's virtualization app models them as
+// first-class citizens alongside physical devices.
+func (i *NetboxData) resolveVM(vmRef *client.VirtualMachine) (*NetboxDevice, error) {
+	vm, err := i.client.VirtualMachines.GetByURL(vmRef.URL)
 	if err != nil {
 		return nil, err
 	}
-	if err := region.parse("region", body); err != nil {
-		return nil, err
+
+	var cluster *client.Cluster
+	var site *client.Site
+	var region *client.Region
+	if vm.Cluster != nil {
+		cluster, err = i.client.Clusters.GetByURL(vm.Cluster.URL)
+		if err != nil {
+			return nil, err
+		}
+		if cluster.Site != nil {
+			site, err = i.client.Sites.GetByURL(cluster.Site.URL)
+			if err != nil {
+				return nil, err
+			}
+			// site was already fetched in full above, so site.Region (if
+			// any) is authoritative; there's no separate region URL to
+			// hop to, and decoding the site's own URL as a Region would
+			// just relabel the site as its own region.
+			region = site.Region
+		}
 	}
+
 	return &NetboxDevice{
-		region: region, site: site, device: device, addedAt: time.Now()}, nil
+		region: region, site: site, vm: vm, cluster: cluster, addedAt: time.Now()}, nil
 }
 
 func (i *NetboxData) get(ip string) (*NetboxDevice, error) {
 	i.Lock()
-	defer i.Unlock()
 	device, ok := i.data[ip]
-	if ok && time.Now().Sub(device.addedAt) <= i.entryTTL {
-		// entry is not stale
-		logPrintf("Found valid cache entry for %s", ip)
-		return device, nil
+	if ok {
+		age := time.Now().Sub(device.addedAt)
+		// softTTL == 0 means no soft tier is configured (including
+		// hand-built NetboxData values in tests): fall back to the single
+		// hard-TTL check below instead of ever enqueuing a refresh.
+		softTTL := i.softTTL
+		if softTTL <= 0 {
+			softTTL = i.entryTTL
+		}
+		if age <= softTTL {
+			// entry is fully fresh
+			i.Unlock()
+			logPrintf("Found valid cache entry for %s", ip)
+			i.stats.cacheHit("ip-to-device")
+			return device, nil
+		}
+		if softTTL < i.entryTTL && age <= i.entryTTL {
+			// soft-expired: serve the stale entry now and refresh it in the
+			// background so no caller blocks on a Netbox round trip.
+			i.Unlock()
+			logPrintf("Serving soft-expired cache entry for %s, refreshing in background", ip)
+			i.stats.cacheHit("ip-to-device")
+			i.enqueueRefresh(ip)
+			return device, nil
+		}
 	}
-	// not in cache or stale, populate from netbox
 	delete(i.data, ip)
+	if missedAt, missed := i.missed[ip]; missed && time.Now().Sub(missedAt) <= i.entryTTL {
+		i.Unlock()
+		return nil, fmt.Errorf("ip %s had no netbox match on last lookup", ip)
+	}
+	trie := i.prefixTrie
+	i.Unlock()
+
+	// In prefix mode, try the in-memory LPM trie before ever doing a
+	// per-IP round trip to Netbox.
+	if trie != nil {
+		if meta := trie.lookup(ip); meta != nil {
+			device := &NetboxDevice{site: meta.site, region: meta.region, tenant: meta.tenant, addedAt: time.Now()}
+			i.Lock()
+			i.data[ip] = device
+			i.Unlock()
+			i.stats.cacheHit("prefix")
+			return device, nil
+		}
+	}
+
+	// not in cache, not stale-but-present, and not in the trie: fall back
+	// to the per-IP device query.
+	i.stats.cacheMiss("ip-to-device")
 	device, err := i.queryNetboxDevice(ip)
 	if err != nil {
+		i.Lock()
+		i.missed[ip] = time.Now()
+		i.Unlock()
 		return nil, err
 	}
+	i.Lock()
 	i.data[ip] = device
+	delete(i.missed, ip)
+	size := len(i.data)
+	i.Unlock()
+	i.stats.setCacheSize("ip-to-device", size)
 	return device, nil
 }
 
+// enqueueRefresh schedules an async re-resolve of ip via the refresh worker
+// pool. It never blocks the caller: an IP already being refreshed, or a full
+// queue, is simply skipped until the next soft-expired hit tries again.
+func (i *NetboxData) enqueueRefresh(ip string) {
+	i.Lock()
+	if i.refreshing[ip] {
+		i.Unlock()
+		return
+	}
+	i.refreshing[ip] = true
+	i.Unlock()
+
+	select {
+	case i.refreshCh <- ip:
+	default:
+		i.Lock()
+		delete(i.refreshing, ip)
+		i.Unlock()
+	}
+}
+
+// refreshWorker drains background refresh jobs, re-resolving each IP against
+// Netbox and updating the cache in place so the next soft-expired hit sees
+// fresh data without ever blocking on the round trip itself.
+func (i *NetboxData) refreshWorker() {
+	for ip := range i.refreshCh {
+		device, err := i.queryNetboxDevice(ip)
+		i.Lock()
+		delete(i.refreshing, ip)
+		if err == nil {
+			i.data[ip] = device
+		}
+		i.Unlock()
+		if err != nil {
+			logPrintf("Background refresh for %s failed: %v", ip, err)
+		}
+	}
+}
+
+// getInterface resolves device+interface metadata, consulting the
+// interfaces cache first (keyed by "device/interface" name, so a hit never
+// touches Netbox at all) before falling through to the device+interface
+// lookups on a miss.
+func (i *NetboxData) getInterface(deviceName, ifName string) (*client.Interface, error) {
+	key := deviceName + "/" + ifName
+	i.Lock()
+	if entry, ok := i.interfaces[key]; ok && time.Now().Sub(entry.addedAt) <= i.entryTTL {
+		i.Unlock()
+		logPrintf("Found valid interface cache entry for %s/%s", deviceName, ifName)
+		i.stats.cacheHit("device-to-interface")
+		return entry.data.(*client.Interface), nil
+	}
+	i.Unlock()
+	i.stats.cacheMiss("device-to-interface")
+
+	device, err := i.client.Devices.GetByName(deviceName)
+	if err != nil {
+		return nil, err
+	}
+	iface, err := i.client.Interfaces.Get(device.ID, ifName)
+	if err != nil {
+		return nil, err
+	}
+	i.Lock()
+	i.interfaces[key] = &cacheEntry{data: iface, addedAt: time.Now()}
+	i.stats.setCacheSize("interfaces", len(i.interfaces))
+	i.Unlock()
+	return iface, nil
+}
+
+// getRack resolves the rack+position+face for a device, pulled straight off
+// the device body since Netbox embeds it there. The cache is keyed by
+// device name, so a hit avoids the device lookup entirely.
+func (i *NetboxData) getRack(deviceName string) (*client.Rack, float64, string, error) {
+	i.Lock()
+	if entry, ok := i.racks[deviceName]; ok && time.Now().Sub(entry.addedAt) <= i.entryTTL {
+		i.Unlock()
+		logPrintf("Found valid rack cache entry for %s", deviceName)
+		i.stats.cacheHit("device-to-rack")
+		rack := entry.data.(*cachedRack)
+		return rack.rack, rack.position, rack.face, nil
+	}
+	i.Unlock()
+	i.stats.cacheMiss("device-to-rack")
+
+	device, err := i.client.Devices.GetByName(deviceName)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	if device.Rack == nil {
+		return nil, 0, "", fmt.Errorf("Device %s has no rack assigned", deviceName)
+	}
+	face := ""
+	if device.Face != nil {
+		face = device.Face.Label
+	}
+
+	i.Lock()
+	i.racks[deviceName] = &cacheEntry{data: &cachedRack{rack: device.Rack, position: device.Position, face: face}, addedAt: time.Now()}
+	i.stats.setCacheSize("racks", len(i.racks))
+	i.Unlock()
+	return device.Rack, device.Position, face, nil
+}
+
+// getTenant resolves the tenant for a device, also pulled off the device
+// body rather than a separate query. The cache is keyed by device name, so
+// a hit avoids the device lookup entirely.
+func (i *NetboxData) getTenant(deviceName string) (*client.Tenant, error) {
+	i.Lock()
+	if entry, ok := i.tenants[deviceName]; ok && time.Now().Sub(entry.addedAt) <= i.entryTTL {
+		i.Unlock()
+		logPrintf("Found valid tenant cache entry for %s", deviceName)
+		i.stats.cacheHit("device-to-tenant")
+		return entry.data.(*client.Tenant), nil
+	}
+	i.Unlock()
+	i.stats.cacheMiss("device-to-tenant")
+
+	device, err := i.client.Devices.GetByName(deviceName)
+	if err != nil {
+		return nil, err
+	}
+	if device.Tenant == nil {
+		return nil, fmt.Errorf("Device %s has no tenant assigned", deviceName)
+	}
+
+	i.Lock()
+	i.tenants[deviceName] = &cacheEntry{data: device.Tenant, addedAt: time.Now()}
+	i.stats.setCacheSize("tenants", len(i.tenants))
+	i.Unlock()
+	return device.Tenant, nil
+}
+
+// getCable resolves the far-side device+interface that an interface is
+// cabled to. The cache is keyed by "device/interface" name and consulted
+// before getInterface, so a hit never issues any Netbox call.
+func (i *NetboxData) getCable(deviceName, ifName string) (*client.Interface, error) {
+	key := deviceName + "/" + ifName
+	i.Lock()
+	if entry, ok := i.cables[key]; ok && time.Now().Sub(entry.addedAt) <= i.entryTTL {
+		i.Unlock()
+		logPrintf("Found valid cable cache entry for %s/%s", deviceName, ifName)
+		i.stats.cacheHit("interface-to-cable")
+		return entry.data.(*client.Interface), nil
+	}
+	i.Unlock()
+	i.stats.cacheMiss("interface-to-cable")
+
+	iface, err := i.getInterface(deviceName, ifName)
+	if err != nil {
+		return nil, err
+	}
+	if iface.InterfaceConnection == nil || iface.InterfaceConnection.Interface == nil {
+		return nil, fmt.Errorf("Interface %s/%s has no cable connection", deviceName, ifName)
+	}
+	farSide := iface.InterfaceConnection.Interface
+
+	i.Lock()
+	i.cables[key] = &cacheEntry{data: farSide, addedAt: time.Now()}
+	i.stats.setCacheSize("cables", len(i.cables))
+	i.Unlock()
+	return farSide, nil
+}
+
+// getConfigContext resolves a device's merged config_context, sharing the
+// same TTL cache infrastructure as the other device lookups.
+func (i *NetboxData) getConfigContext(deviceName string) (map[string]interface{}, error) {
+	device, err := i.client.Devices.GetByName(deviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	i.Lock()
+	if entry, ok := i.configContexts[device.ID]; ok && time.Now().Sub(entry.addedAt) <= i.entryTTL {
+		i.Unlock()
+		logPrintf("Found valid config-context cache entry for %s", deviceName)
+		i.stats.cacheHit("device-to-config-context")
+		return entry.data.(map[string]interface{}), nil
+	}
+	i.Unlock()
+	i.stats.cacheMiss("device-to-config-context")
+
+	full, err := i.client.Devices.GetConfigContext(device.ID)
+	if err != nil {
+		return nil, err
+	}
+	i.Lock()
+	defer i.Unlock()
+	i.configContexts[device.ID] = &cacheEntry{data: full.ConfigContext, addedAt: time.Now()}
+	i.stats.setCacheSize("config-contexts", len(i.configContexts))
+	return full.ConfigContext, nil
+}
+
 type params struct {
-	NetboxAddr       string `toml:"netbox_addr"`
-	NetboxToken      string `toml:"netbox_token"`
-	PreserveOriginal bool   `toml:"preserve_original"`
-	EntryTtl         string `toml:"entry_ttl"`
+	NetboxAddr            string `toml:"netbox_addr"`
+	NetboxToken           string `toml:"netbox_token"`
+	NetboxApiVersion      string `toml:"netbox_api_version"`
+	PreserveOriginal      bool   `toml:"preserve_original"`
+	EntryTtl              string `toml:"entry_ttl"`
+	SoftEntryTtl          string `toml:"soft_entry_ttl"`
+	RefreshWorkers        int    `toml:"refresh_workers"`
+	PrefixMode            bool   `toml:"prefix_mode"`
+	PrefixRefreshInterval string `toml:"prefix_refresh_interval"`
+	CachePath             string `toml:"cache_path"`
+	CacheFlushInterval    string `toml:"cache_flush_interval"`
 }
 
 type Netbox struct {
-	Params      *params
-	Transforms  map[string][]string `toml:"transforms"`
+	Params            *params
+	Transforms        map[string][]string `toml:"transforms"`
+	ConfigContextTags []string            `toml:"config_context_tags"`
+	tlsint.ClientConfig
+
 	netboxData  *NetboxData
 	initialized bool
 }
@@ -185,78 +565,246 @@ func (n *Netbox) Description() string {
 	return "Apply transforms to specific tags based on Netbox data"
 }
 
+// defaultRefreshWorkers bounds the background worker pool used to refresh
+// soft-expired cache entries when refresh_workers isn't configured.
+const defaultRefreshWorkers = 4
+
 func (n *Netbox) init() {
 	ttl, err := time.ParseDuration(n.Params.EntryTtl)
 	if err != nil {
 		logPrintf("Invalid or no cache TTL specified, using default 4h")
 		ttl = time.Duration(4 * time.Hour)
 	}
-	tr := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	// softTTL governs when a cache entry is served stale-but-immediately
+	// while a refresh happens in the background; hardTTL (entryTTL above)
+	// is when a lookup blocks and refetches instead. Defaulting softTTL to
+	// the hard TTL disables the soft window, matching the old blocking
+	// behavior for configs that don't set soft_entry_ttl.
+	softTTL := ttl
+	if n.Params.SoftEntryTtl != "" {
+		softTTL, err = time.ParseDuration(n.Params.SoftEntryTtl)
+		if err != nil {
+			logPrintf("Invalid soft_entry_ttl specified, disabling soft expiry")
+			softTTL = ttl
+		}
+	}
+	refreshWorkers := n.Params.RefreshWorkers
+	if refreshWorkers <= 0 {
+		refreshWorkers = defaultRefreshWorkers
+	}
+	apiVersion := client.APIVersion(n.Params.NetboxApiVersion)
+	if apiVersion != client.APIVersion2 && apiVersion != client.APIVersion3 {
+		logPrintf("Invalid or no netbox_api_version specified, defaulting to %s", client.APIVersion2)
+		apiVersion = client.APIVersion2
+	}
+	tlsCfg, err := n.ClientConfig.TLSConfig()
+	if err != nil {
+		logPrintf("Invalid TLS config, falling back to defaults: %v", err)
+		tlsCfg = &tls.Config{}
+	}
+	tr := &http.Transport{TLSClientConfig: tlsCfg}
+	httpClient := &http.Client{Transport: tr}
+	stats := newNetboxStats()
+	netboxClient := client.New(n.Params.NetboxAddr, n.Params.NetboxToken, apiVersion, httpClient)
+	netboxClient.SetObserver(stats)
 	n.netboxData = &NetboxData{
-		data:        make(map[string]*NetboxDevice),
-		entryTTL:    ttl,
-		netboxAddr:  n.Params.NetboxAddr,
-		netboxToken: n.Params.NetboxToken,
-		client:      &Client{Client: &http.Client{Transport: tr}}}
+		data:           make(map[string]*NetboxDevice),
+		interfaces:     make(map[string]*cacheEntry),
+		racks:          make(map[string]*cacheEntry),
+		tenants:        make(map[string]*cacheEntry),
+		cables:         make(map[string]*cacheEntry),
+		configContexts: make(map[int]*cacheEntry),
+		missed:         make(map[string]time.Time),
+		softTTL:        softTTL,
+		entryTTL:       ttl,
+		cachePath:      n.Params.CachePath,
+		refreshCh:      make(chan string, 1024),
+		refreshing:     make(map[string]bool),
+		client:         netboxClient,
+		stats:          stats,
+	}
+	for w := 0; w < refreshWorkers; w++ {
+		go n.netboxData.refreshWorker()
+	}
+	if n.Params.CachePath != "" {
+		n.netboxData.loadPersistedCache()
+		flushInterval, err := time.ParseDuration(n.Params.CacheFlushInterval)
+		if err != nil {
+			logPrintf("Invalid or no cache_flush_interval specified, using default 5m")
+			flushInterval = 5 * time.Minute
+		}
+		go n.netboxData.persistLoop(flushInterval)
+	}
+	if n.Params.PrefixMode {
+		refresh, err := time.ParseDuration(n.Params.PrefixRefreshInterval)
+		if err != nil {
+			logPrintf("Invalid or no prefix_refresh_interval specified, using default 30m")
+			refresh = 30 * time.Minute
+		}
+		n.netboxData.refreshPrefixTrie()
+		go n.netboxData.prefixRefreshLoop(refresh)
+	}
 	n.initialized = true
 }
 
-func (n *Netbox) transformForTag(tagKey string) string {
+// transformsForTag returns every transform configured against tagKey, since
+// multiple device-keyed transforms (e.g. device-to-rack and device-to-tenant)
+// commonly share the same tag and all need to fire for a single metric.
+func (n *Netbox) transformsForTag(tagKey string) []string {
+	var matched []string
 	for transform, tags := range n.Transforms {
 		for _, tag := range tags {
 			if tag == tagKey {
-				return transform
+				matched = append(matched, transform)
+				break
 			}
 		}
 	}
-	return ""
+	return matched
 }
 
+// newTagsForIp resolves an ip-to-device or ip-to-vm tag. ip-to-device falls
+// through to the VM tags transparently when the ip's parent turns out to be
+// a VM rather than a physical device, since both are resolved the same way
+// under the hood.
 func (n *Netbox) newTagsForIp(key, value string) map[string]string {
-	newTags := make(map[string]string, 3)
+	newTags := make(map[string]string, 4)
 	netboxDevice, err := n.netboxData.get(value)
 	if err != nil {
 		logPrintf("Unable to get netbox data for ip: %s: %v\n", value, err)
 		return newTags
 	}
-	deviceKey := "device"
-	siteKey := "site"
-	regionKey := "region"
+	prefix := ""
 	if strings.HasPrefix(key, "source") {
-		deviceKey = "source-" + deviceKey
-		siteKey = "source-" + siteKey
-		regionKey = "source-" + regionKey
+		prefix = "source-"
 	} else if strings.HasPrefix(key, "destination") {
-		deviceKey = "destination-" + deviceKey
-		siteKey = "destination-" + siteKey
-		regionKey = "destination-" + regionKey
+		prefix = "destination-"
+	}
+	if netboxDevice.site != nil {
+		newTags[prefix+"site"] = netboxDevice.site.Name
+	}
+	if netboxDevice.region != nil {
+		newTags[prefix+"region"] = netboxDevice.region.Name
+	}
+	if netboxDevice.tenant != nil {
+		newTags[prefix+"tenant"] = netboxDevice.tenant.Name
+	}
+	if netboxDevice.device != nil {
+		newTags[prefix+"device"] = netboxDevice.device.Name
+	} else if netboxDevice.vm != nil {
+		newTags[prefix+"vm"] = netboxDevice.vm.Name
+		if netboxDevice.cluster != nil {
+			newTags[prefix+"cluster"] = netboxDevice.cluster.Name
+		}
 	}
-	newTags[deviceKey] = netboxDevice.device.name
-	newTags[siteKey] = netboxDevice.site.name
-	newTags[regionKey] = netboxDevice.region.name
 	return newTags
 }
 
+// applyDeviceTransform handles the transforms that key off a device name
+// (and, for device-to-interface/interface-to-cable, an interface name)
+// rather than an individual tag value. These need more than one tag
+// present on the metric at once, so they read the device (and interface)
+// tags straight off the metric instead of using the tag value that
+// triggered them.
+func (n *Netbox) applyDeviceTransform(transform string, metric telegraf.Metric) {
+	deviceName, ok := metric.Tags()["device"]
+	if !ok {
+		return
+	}
+	switch transform {
+	case "device-to-interface":
+		ifName, ok := metric.Tags()["interface"]
+		if !ok {
+			return
+		}
+		iface, err := n.netboxData.getInterface(deviceName, ifName)
+		if err != nil {
+			logPrintf("Unable to get netbox interface data for %s/%s: %v\n", deviceName, ifName, err)
+			return
+		}
+		lag := ""
+		if iface.Lag != nil {
+			lag = iface.Lag.Name
+		}
+		formFactor := ""
+		if iface.FormFactor != nil {
+			formFactor = iface.FormFactor.Label
+		}
+		metric.AddTag("interface-mtu", fmt.Sprintf("%v", iface.MTU))
+		metric.AddTag("interface-mac", iface.MACAddress)
+		metric.AddTag("interface-lag", lag)
+		metric.AddTag("interface-form-factor", formFactor)
+	case "device-to-rack":
+		rack, position, face, err := n.netboxData.getRack(deviceName)
+		if err != nil {
+			logPrintf("Unable to get netbox rack data for %s: %v\n", deviceName, err)
+			return
+		}
+		metric.AddTag("rack", rack.Name)
+		metric.AddTag("position", fmt.Sprintf("%v", position))
+		metric.AddTag("face", face)
+	case "device-to-tenant":
+		tenant, err := n.netboxData.getTenant(deviceName)
+		if err != nil {
+			logPrintf("Unable to get netbox tenant data for %s: %v\n", deviceName, err)
+			return
+		}
+		metric.AddTag("tenant", tenant.Name)
+	case "interface-to-cable":
+		ifName, ok := metric.Tags()["interface"]
+		if !ok {
+			return
+		}
+		farSide, err := n.netboxData.getCable(deviceName, ifName)
+		if err != nil {
+			logPrintf("Unable to get netbox cable data for %s/%s: %v\n", deviceName, ifName, err)
+			return
+		}
+		farDevice := ""
+		if farSide.Device != nil {
+			farDevice = farSide.Device.Name
+		}
+		metric.AddTag("far-device", farDevice)
+		metric.AddTag("far-interface", farSide.Name)
+	case "device-to-config-context":
+		context, err := n.netboxData.getConfigContext(deviceName)
+		if err != nil {
+			logPrintf("Unable to get netbox config-context data for %s: %v\n", deviceName, err)
+			return
+		}
+		for _, tag := range n.ConfigContextTags {
+			if v, ok := context[tag]; ok {
+				metric.AddTag(tag, fmt.Sprintf("%v", v))
+			}
+		}
+	}
+}
+
 func (n *Netbox) Apply(metrics ...telegraf.Metric) []telegraf.Metric {
 	if !n.initialized {
 		n.init()
 	}
 	for _, metric := range metrics {
 		for tagKey, tagValue := range metric.Tags() {
-			xform := n.transformForTag(tagKey)
-			switch xform {
-			case "ip-to-device":
-				newTags := n.newTagsForIp(tagKey, tagValue)
-				for k, v := range newTags {
-					metric.AddTag(k, v)
-				}
-				if !n.Params.PreserveOriginal {
-					metric.RemoveTag(tagKey)
-				}
-			default:
+			xforms := n.transformsForTag(tagKey)
+			if len(xforms) == 0 {
 				logPrintf("No supported transform found for tag key: %s\n", tagKey)
 				continue
 			}
+			for _, xform := range xforms {
+				switch xform {
+				case "ip-to-device", "ip-to-vm":
+					newTags := n.newTagsForIp(tagKey, tagValue)
+					for k, v := range newTags {
+						metric.AddTag(k, v)
+					}
+					if !n.Params.PreserveOriginal {
+						metric.RemoveTag(tagKey)
+					}
+				case "device-to-interface", "device-to-rack", "device-to-tenant", "interface-to-cable", "device-to-config-context":
+					n.applyDeviceTransform(xform, metric)
+				}
+			}
 		}
 	}
 	return metrics
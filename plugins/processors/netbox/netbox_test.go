@@ -6,33 +6,10 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors/netbox/client"
 	"github.com/stretchr/testify/assert"
 )
 
-var ipRaw = `{"count":1,"next":null,"previous":null,"results":[{"id":1531,"family":4,"address":"141.193.3.5/32","vrf":null,"tenant":null,"status":{"value":1,"label":"Active"},"role":null,"interface":{"id":42419,"device":{"id":1019,"url":"https://netbox.roblox.local/api/dcim/devices/1019/","name":"br1-sjc1","display_name":"br1-sjc1"},"name":"lo0.0","form_factor":{"value":0,"label":"Virtual"},"enabled":true,"lag":null,"mtu":null,"mac_address":null,"mgmt_only":false,"description":"","is_connected":false,"interface_connection":null,"circuit_termination":null},"description":"","nat_inside":null,"nat_outside":null,"custom_fields":{}}]}`
-
-var deviceRaw = `{"id":3706,"name":"br1-fra1","display_name":"br1-fra1","device_type":{"id":33,"url":"https://netbox.roblox.local/api/dcim/device-types/33/","manufacturer":{"id":5,"url":"https://netbox.roblox.local/api/dcim/manufacturers/5/","name":"Juniper","slug":"juniper"},"model":"PTX1000","slug":"ptx1000"},"device_role":{"id":58,"url":"https://netbox.roblox.local/api/dcim/device-roles/58/","name":"border-router","slug":"border-router"},"tenant":null,"platform":{"id":3,"url":"https://netbox.roblox.local/api/dcim/platforms/3/","name":"Junos","slug":"junos"},"serial":"DQ077","asset_tag":"AAAAAAACDP","site":{"id":14,"url":"https://netbox.roblox.local/api/dcim/sites/14/","name":"fra1","slug":"fra1"},"rack":{"id":230,"url":"https://netbox.roblox.local/api/dcim/racks/230/","name":"AF04","display_name":"AF04 (FR6:02:202073.101)"},"position":28,"face":{"value":0,"label":"Front"},"parent_device":null,"status":{"value":1,"label":"Active"},"primary_ip":{"id":5306,"url":"https://netbox.roblox.local/api/ipam/ip-addresses/5306/","family":4,"address":"141.193.3.9/32"},"primary_ip4":{"id":5306,"url":"https://netbox.roblox.local/api/ipam/ip-addresses/5306/","family":4,"address":"141.193.3.9/32"},"primary_ip6":null,"cluster":null,"comments":"","custom_fields":{"service_group":null,"roblox_sku":null,"roblox_po":null,"ASN":22697,"design_rev":"br-pop-ptx-revA"}}`
-
-func TestNetboxParsers(t *testing.T) {
-	device := &NetboxElement{}
-	err := device.parse("device", []byte(ipRaw))
-	if err != nil {
-		t.FailNow()
-	}
-	assert.Equal(t, int(device.id), 1019)
-	assert.Equal(t, device.name, "br1-sjc1")
-	assert.Equal(t, device.url, "https://netbox.roblox.local/api/dcim/devices/1019/")
-
-	site := &NetboxElement{}
-	err = site.parse("site", []byte(deviceRaw))
-	if err != nil {
-		t.FailNow()
-	}
-	assert.Equal(t, int(site.id), 14)
-	assert.Equal(t, site.name, "fra1")
-	assert.Equal(t, site.url, "https://netbox.roblox.local/api/dcim/sites/14/")
-}
-
 func newM1() telegraf.Metric {
 	m1, _ := metric.New("metric_1",
 		map[string]string{"name": "lsp1", "source-address": "141.193.3.5", "destination-address": "12.100.16.2"},
@@ -59,15 +36,15 @@ func NewNetbox() *Netbox {
 			entryTTL: ttl,
 			data: map[string]*NetboxDevice{
 				"141.193.3.5": &NetboxDevice{
-					region:  &NetboxElement{name: "US_WEST"},
-					site:    &NetboxElement{name: "sjc1"},
-					device:  &NetboxElement{name: "br1-sjc1"},
+					region:  &client.Region{Name: "US_WEST"},
+					site:    &client.Site{Name: "sjc1"},
+					device:  &client.Device{Name: "br1-sjc1"},
 					addedAt: time.Now(),
 				},
 				"12.100.16.2": &NetboxDevice{
-					region:  &NetboxElement{name: "US_EAST"},
-					site:    &NetboxElement{name: "ash1"},
-					device:  &NetboxElement{name: "br1-iad1"},
+					region:  &client.Region{Name: "US_EAST"},
+					site:    &client.Site{Name: "ash1"},
+					device:  &client.Device{Name: "br1-iad1"},
 					addedAt: time.Now(),
 				},
 			},
@@ -76,6 +53,96 @@ func NewNetbox() *Netbox {
 	return netbox
 }
 
+func newM3() telegraf.Metric {
+	m3, _ := metric.New("metric_3",
+		map[string]string{"name": "lsp1", "source-address": "10.0.0.5"},
+		map[string]interface{}{"packets": 12345},
+		time.Now())
+	return m3
+}
+
+func TestTagIpToDeviceFallsThroughToVM(t *testing.T) {
+	ttl, _ := time.ParseDuration("1h")
+	netbox := &Netbox{
+		Params:      &params{PreserveOriginal: true},
+		Transforms:  map[string][]string{"ip-to-device": []string{"source-address"}},
+		initialized: true,
+		netboxData: &NetboxData{
+			entryTTL: ttl,
+			data: map[string]*NetboxDevice{
+				"10.0.0.5": &NetboxDevice{
+					region:  &client.Region{Name: "US_WEST"},
+					site:    &client.Site{Name: "sjc1"},
+					vm:      &client.VirtualMachine{Name: "vm1-sjc1"},
+					cluster: &client.Cluster{Name: "sjc1-cluster1"},
+					addedAt: time.Now(),
+				},
+			},
+		},
+	}
+	m3 := newM3()
+	new := netbox.Apply(m3)
+
+	assert.Equal(t, getTag(new[0], "source-vm"), "vm1-sjc1")
+	assert.Equal(t, getTag(new[0], "source-cluster"), "sjc1-cluster1")
+	assert.Equal(t, getTag(new[0], "source-site"), "sjc1")
+	assert.Equal(t, getTag(new[0], "source-device"), "")
+}
+
+func TestTagIpTransformPrefixMode(t *testing.T) {
+	ttl, _ := time.ParseDuration("1h")
+	tr := &trie{}
+	tr.insert("10.0.0.0/8", &prefixMeta{
+		site:   &client.Site{Name: "sjc1"},
+		region: &client.Region{Name: "US_WEST"},
+		tenant: &client.Tenant{Name: "team-network"},
+	})
+	netbox := &Netbox{
+		Params:      &params{PreserveOriginal: true},
+		Transforms:  map[string][]string{"ip-to-device": []string{"source-address"}},
+		initialized: true,
+		netboxData: &NetboxData{
+			entryTTL:   ttl,
+			data:       make(map[string]*NetboxDevice),
+			missed:     make(map[string]time.Time),
+			prefixTrie: tr,
+		},
+	}
+	m3 := newM3()
+	new := netbox.Apply(m3)
+
+	assert.Equal(t, getTag(new[0], "source-site"), "sjc1")
+	assert.Equal(t, getTag(new[0], "source-region"), "US_WEST")
+	assert.Equal(t, getTag(new[0], "source-tenant"), "team-network")
+	assert.Equal(t, getTag(new[0], "source-device"), "")
+}
+
+func TestGetServesSoftExpiredEntryAndEnqueuesRefresh(t *testing.T) {
+	softTTL, _ := time.ParseDuration("1ms")
+	hardTTL, _ := time.ParseDuration("1h")
+	data := &NetboxData{
+		softTTL:    softTTL,
+		entryTTL:   hardTTL,
+		data:       map[string]*NetboxDevice{},
+		missed:     make(map[string]time.Time),
+		refreshCh:  make(chan string, 1),
+		refreshing: make(map[string]bool),
+	}
+	stale := &NetboxDevice{site: &client.Site{Name: "sjc1"}, addedAt: time.Now().Add(-time.Hour / 2)}
+	data.data["10.0.0.1"] = stale
+
+	device, err := data.get("10.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, device.site.Name, "sjc1")
+
+	select {
+	case ip := <-data.refreshCh:
+		assert.Equal(t, ip, "10.0.0.1")
+	default:
+		t.Fatal("expected a background refresh to be enqueued for the soft-expired entry")
+	}
+}
+
 func getTag(metric telegraf.Metric, tag string) string {
 	for key, value := range metric.Tags() {
 		if key == tag {
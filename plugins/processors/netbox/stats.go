@@ -0,0 +1,112 @@
+package netbox
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// netboxStats exposes the processor's internal counters as selfstat gauges,
+// which the internal input plugin surfaces under the "internal_netbox"
+// measurement. It also implements client.RequestObserver so every Netbox
+// HTTP round trip is instrumented without the client package depending on
+// telegraf's stats machinery.
+type netboxStats struct {
+	mu sync.Mutex
+
+	cacheHits   map[string]selfstat.Stat
+	cacheMisses map[string]selfstat.Stat
+	cacheSize   map[string]selfstat.Stat
+	httpErrors  map[string]selfstat.Stat
+
+	httpRequests    selfstat.Stat
+	httpRequestMs   selfstat.Stat
+	refreshFailures selfstat.Stat
+}
+
+func newNetboxStats() *netboxStats {
+	return &netboxStats{
+		cacheHits:       make(map[string]selfstat.Stat),
+		cacheMisses:     make(map[string]selfstat.Stat),
+		cacheSize:       make(map[string]selfstat.Stat),
+		httpErrors:      make(map[string]selfstat.Stat),
+		httpRequests:    selfstat.Register("netbox", "http_requests", map[string]string{}),
+		httpRequestMs:   selfstat.Register("netbox", "http_request_ms", map[string]string{}),
+		refreshFailures: selfstat.Register("netbox", "refresh_failures", map[string]string{}),
+	}
+}
+
+// Every method is nil-safe so tests can construct a NetboxData without
+// wiring up stats.
+
+func (s *netboxStats) cacheHit(transform string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stat, ok := s.cacheHits[transform]
+	if !ok {
+		stat = selfstat.Register("netbox", "cache_hits", map[string]string{"transform": transform})
+		s.cacheHits[transform] = stat
+	}
+	stat.Incr(1)
+}
+
+func (s *netboxStats) cacheMiss(transform string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stat, ok := s.cacheMisses[transform]
+	if !ok {
+		stat = selfstat.Register("netbox", "cache_misses", map[string]string{"transform": transform})
+		s.cacheMisses[transform] = stat
+	}
+	stat.Incr(1)
+}
+
+func (s *netboxStats) setCacheSize(entity string, size int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stat, ok := s.cacheSize[entity]
+	if !ok {
+		stat = selfstat.Register("netbox", "cache_size", map[string]string{"entity": entity})
+		s.cacheSize[entity] = stat
+	}
+	stat.Set(int64(size))
+}
+
+func (s *netboxStats) incrRefreshFailure() {
+	if s == nil {
+		return
+	}
+	s.refreshFailures.Incr(1)
+}
+
+// ObserveRequest implements client.RequestObserver.
+func (s *netboxStats) ObserveRequest(path string, statusCode int, duration time.Duration, err error) {
+	if s == nil {
+		return
+	}
+	s.httpRequests.Incr(1)
+	s.httpRequestMs.Incr(duration.Milliseconds())
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code := strconv.Itoa(statusCode)
+	stat, ok := s.httpErrors[code]
+	if !ok {
+		stat = selfstat.Register("netbox", "http_errors", map[string]string{"status_code": code})
+		s.httpErrors[code] = stat
+	}
+	stat.Incr(1)
+}